@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/tg123/go-htpasswd"
+)
+
+// Auth authenticates a basic-auth username/password pair against a user
+// store.
+type Auth interface {
+	Authenticate(user, pass string) bool
+}
+
+// multiAuth tries each provider in order, succeeding if any of them accepts
+// the credentials. This lets --users and --users-file be combined.
+type multiAuth []Auth
+
+func (m multiAuth) Authenticate(user, pass string) bool {
+	for _, a := range m {
+		if a.Authenticate(user, pass) {
+			return true
+		}
+	}
+	return false
+}
+
+// staticAuth holds the in-memory username/password pairs configured via
+// --users. Comparisons use crypto/subtle.ConstantTimeCompare so a failed
+// match doesn't leak timing information about how many characters matched.
+type staticAuth struct {
+	users []User
+}
+
+func newStaticAuth(users []User) *staticAuth {
+	return &staticAuth{users: users}
+}
+
+func (a *staticAuth) Authenticate(user, pass string) bool {
+	for _, u := range a.users {
+		nameOK := subtle.ConstantTimeCompare([]byte(user), []byte(u.Name)) == 1
+		passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(u.Password)) == 1
+		if nameOK && passOK {
+			return true
+		}
+	}
+	return false
+}
+
+// fileAuth authenticates against an htpasswd-style file, supporting bcrypt,
+// SHA, and MD5 hashed entries. The file is periodically re-read under
+// mutex so operators can add or remove users without restarting the
+// service.
+type fileAuth struct {
+	mutex  sync.RWMutex
+	file   *htpasswd.File
+	path   string
+	logger *slog.Logger
+}
+
+func newFileAuth(path string, reloadInterval time.Duration, logger *slog.Logger) (*fileAuth, error) {
+	a := &fileAuth{path: path, logger: logger}
+
+	file, err := htpasswd.New(path, htpasswd.DefaultSystems, func(err error) {
+		logger.Error("parse htpasswd entry", "path", path, "error", err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load htpasswd file: %w", err)
+	}
+	a.file = file
+
+	if reloadInterval > 0 {
+		go a.reloadLoop(reloadInterval)
+	}
+	return a, nil
+}
+
+func (a *fileAuth) reloadLoop(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		if err := a.reload(); err != nil {
+			a.logger.Error("reload htpasswd file", "path", a.path, "error", err)
+		}
+	}
+}
+
+func (a *fileAuth) reload() error {
+	file, err := htpasswd.New(a.path, htpasswd.DefaultSystems, func(err error) {
+		a.logger.Error("parse htpasswd entry", "path", a.path, "error", err)
+	})
+	if err != nil {
+		return err
+	}
+
+	a.mutex.Lock()
+	a.file = file
+	a.mutex.Unlock()
+
+	a.logger.Debug("reloaded htpasswd file", "path", a.path)
+	return nil
+}
+
+func (a *fileAuth) Authenticate(user, pass string) bool {
+	a.mutex.RLock()
+	file := a.file
+	a.mutex.RUnlock()
+
+	return file.Match(user, pass)
+}
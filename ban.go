@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// banState is the per-IP bookkeeping shared by every BanStore
+// implementation. It is serialized as-is by the BoltDB and Redis backends.
+type banState struct {
+	FailCount   int       `json:"fail_count"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	BannedUntil time.Time `json:"banned_until"`
+}
+
+// BanRecord is a banState together with the address it belongs to, returned
+// by BanStore.List for the admin endpoint.
+type BanRecord struct {
+	Addr netip.Addr
+	banState
+}
+
+// BanStore tracks failed basic-auth attempts per IP and decides how long an
+// IP stays banned. Implementations must be safe for concurrent use.
+type BanStore interface {
+	// RecordFailure registers a failed basic-auth attempt for addr and
+	// returns the time the resulting ban (if any) expires.
+	RecordFailure(addr netip.Addr) (bannedUntil time.Time, err error)
+	// IsBanned reports whether addr is currently banned.
+	IsBanned(addr netip.Addr) (bool, error)
+	// Unban clears all failure history for addr.
+	Unban(addr netip.Addr) error
+	// List returns every tracked entry, banned or not.
+	List() ([]BanRecord, error)
+}
+
+// banExpiry computes base*2^over capped at max, guarding against shift
+// overflow for pathologically large over values.
+func banExpiry(now time.Time, base, max time.Duration, over int) time.Time {
+	if over <= 0 {
+		return now.Add(base)
+	}
+	if over > 32 { // 2^32 * base overflows long before this in practice
+		return now.Add(max)
+	}
+
+	backoff := base << uint(over)
+	if backoff <= 0 || backoff > max {
+		backoff = max
+	}
+	return now.Add(backoff)
+}
+
+// memoryBanStore is the default BanStore: an in-process map guarded by a
+// mutex. Entries do not survive a restart.
+type memoryBanStore struct {
+	mutex     sync.RWMutex
+	entries   map[netip.Addr]*banState
+	threshold int
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+func newMemoryBanStore(threshold int, baseDelay, maxDelay time.Duration) *memoryBanStore {
+	return &memoryBanStore{
+		entries:   make(map[netip.Addr]*banState),
+		threshold: threshold,
+		baseDelay: baseDelay,
+		maxDelay:  maxDelay,
+	}
+}
+
+func (s *memoryBanStore) RecordFailure(addr netip.Addr) (time.Time, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	state, ok := s.entries[addr]
+	if !ok {
+		state = &banState{FirstSeen: time.Now()}
+		s.entries[addr] = state
+	}
+
+	now := time.Now()
+	state.FailCount++
+	state.LastSeen = now
+	if state.FailCount >= s.threshold {
+		state.BannedUntil = banExpiry(now, s.baseDelay, s.maxDelay, state.FailCount-s.threshold)
+	}
+
+	return state.BannedUntil, nil
+}
+
+func (s *memoryBanStore) IsBanned(addr netip.Addr) (bool, error) {
+	s.mutex.RLock()
+	state, ok := s.entries[addr]
+	s.mutex.RUnlock()
+
+	if !ok {
+		return false, nil
+	}
+	return time.Now().Before(state.BannedUntil), nil
+}
+
+func (s *memoryBanStore) Unban(addr netip.Addr) error {
+	s.mutex.Lock()
+	delete(s.entries, addr)
+	s.mutex.Unlock()
+	return nil
+}
+
+func (s *memoryBanStore) List() ([]BanRecord, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	records := make([]BanRecord, 0, len(s.entries))
+	for addr, state := range s.entries {
+		records = append(records, BanRecord{Addr: addr, banState: *state})
+	}
+	return records, nil
+}
+
+// AdminBansHandler gates AdminBans behind the same access control as the
+// proxied path (trusted CIDR/cert allowlist or basic auth), and wraps it the
+// same way ProxyRequestHandler wraps the proxy so admin/bans requests
+// (including unbans, which are security-relevant) show up in the access
+// log too. Without the access control, any anonymous client could
+// enumerate banned IPs and unban them at will, making the ban mechanism
+// pointless.
+func (c *Controller) AdminBansHandler(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	r, state := withRequestState(r)
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+	if err := c.HandleIP(sw, r); err == nil {
+		c.AdminBans(sw, r)
+	}
+
+	c.logAccess(r, state, sw.status, time.Since(start))
+}
+
+// AdminBans lists (GET) or clears (DELETE, ?addr=<ip>) ban-store entries.
+// It is mounted at <status-path>/admin/bans, behind AdminBansHandler.
+func (c *Controller) AdminBans(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		records, err := c.bans.List()
+		if err != nil {
+			slog.Error("list ban store", "error", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		for _, rec := range records {
+			banned := time.Now().Before(rec.BannedUntil)
+			fmt.Fprintf(w, "addr=%s fail_count=%d banned=%t banned_until=%s\n",
+				rec.Addr, rec.FailCount, banned, rec.BannedUntil.Format(time.RFC3339))
+		}
+
+	case http.MethodDelete:
+		addr, err := netip.ParseAddr(r.URL.Query().Get("addr"))
+		if err != nil {
+			http.Error(w, "invalid or missing addr query parameter", http.StatusBadRequest)
+			return
+		}
+
+		if err := c.bans.Unban(addr); err != nil {
+			slog.Error("unban", "addr", addr, "error", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		slog.Info("admin unban", "addr", addr)
+
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
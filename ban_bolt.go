@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var banBucket = []byte("bans")
+
+// boltBanStore persists ban state in a BoltDB file so bans survive a
+// restart of this single instance.
+type boltBanStore struct {
+	db        *bolt.DB
+	threshold int
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+func newBoltBanStore(path string, threshold int, baseDelay, maxDelay time.Duration) (*boltBanStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open bolt db: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(banBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create bolt bucket: %w", err)
+	}
+
+	return &boltBanStore{db: db, threshold: threshold, baseDelay: baseDelay, maxDelay: maxDelay}, nil
+}
+
+func (s *boltBanStore) RecordFailure(addr netip.Addr) (time.Time, error) {
+	var bannedUntil time.Time
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(banBucket)
+
+		state, err := getBanState(bucket, addr)
+		if err != nil {
+			return err
+		}
+		if state.FirstSeen.IsZero() {
+			state.FirstSeen = time.Now()
+		}
+
+		now := time.Now()
+		state.FailCount++
+		state.LastSeen = now
+		if state.FailCount >= s.threshold {
+			state.BannedUntil = banExpiry(now, s.baseDelay, s.maxDelay, state.FailCount-s.threshold)
+		}
+		bannedUntil = state.BannedUntil
+
+		return putBanState(bucket, addr, state)
+	})
+
+	return bannedUntil, err
+}
+
+func (s *boltBanStore) IsBanned(addr netip.Addr) (bool, error) {
+	var banned bool
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		state, err := getBanState(tx.Bucket(banBucket), addr)
+		if err != nil {
+			return err
+		}
+		banned = time.Now().Before(state.BannedUntil)
+		return nil
+	})
+
+	return banned, err
+}
+
+func (s *boltBanStore) Unban(addr netip.Addr) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(banBucket).Delete(addr.AsSlice())
+	})
+}
+
+func (s *boltBanStore) List() ([]BanRecord, error) {
+	var records []BanRecord
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(banBucket).ForEach(func(k, v []byte) error {
+			addr, ok := netip.AddrFromSlice(k)
+			if !ok {
+				return nil
+			}
+
+			var state banState
+			if err := json.Unmarshal(v, &state); err != nil {
+				return fmt.Errorf("decode ban entry (addr=%s): %w", addr, err)
+			}
+
+			records = append(records, BanRecord{Addr: addr, banState: state})
+			return nil
+		})
+	})
+
+	return records, err
+}
+
+func getBanState(bucket *bolt.Bucket, addr netip.Addr) (banState, error) {
+	var state banState
+
+	raw := bucket.Get(addr.AsSlice())
+	if raw == nil {
+		return state, nil
+	}
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return state, fmt.Errorf("decode ban entry: %w", err)
+	}
+	return state, nil
+}
+
+func putBanState(bucket *bolt.Bucket, addr netip.Addr, state banState) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encode ban entry: %w", err)
+	}
+	return bucket.Put(addr.AsSlice(), raw)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const banKeyPrefix = "basic-ip-auth:ban:"
+
+// redisBanStore persists ban state in Redis so bans are shared across a
+// fleet of basic-ip-auth instances fronting the same backend. Each entry is
+// stored with a TTL of maxDelay so stale entries expire on their own.
+type redisBanStore struct {
+	client    *redis.Client
+	threshold int
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+func newRedisBanStore(addr string, threshold int, baseDelay, maxDelay time.Duration) *redisBanStore {
+	return &redisBanStore{
+		client:    redis.NewClient(&redis.Options{Addr: addr}),
+		threshold: threshold,
+		baseDelay: baseDelay,
+		maxDelay:  maxDelay,
+	}
+}
+
+func (s *redisBanStore) key(addr netip.Addr) string {
+	return banKeyPrefix + addr.String()
+}
+
+// redisBanStoreRetries bounds how many times RecordFailure retries its
+// WATCH/MULTI transaction when another instance updates the same key
+// concurrently, before giving up.
+const redisBanStoreRetries = 5
+
+// RecordFailure increments the failure count under a WATCH/MULTI
+// transaction. A plain get-then-set would let two instances racing on the
+// same IP both read the same FailCount and clobber each other's increment,
+// letting an attacker exceed --max-attempts before a ban engages.
+func (s *redisBanStore) RecordFailure(addr netip.Addr) (time.Time, error) {
+	ctx := context.Background()
+	key := s.key(addr)
+
+	var bannedUntil time.Time
+	txf := func(tx *redis.Tx) error {
+		state, err := s.getCmdable(ctx, tx, addr)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if state.FirstSeen.IsZero() {
+			state.FirstSeen = now
+		}
+		state.FailCount++
+		state.LastSeen = now
+		if state.FailCount >= s.threshold {
+			state.BannedUntil = banExpiry(now, s.baseDelay, s.maxDelay, state.FailCount-s.threshold)
+		}
+		bannedUntil = state.BannedUntil
+
+		raw, err := json.Marshal(state)
+		if err != nil {
+			return fmt.Errorf("encode ban entry: %w", err)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, raw, s.maxDelay)
+			return nil
+		})
+		return err
+	}
+
+	for attempt := 0; attempt < redisBanStoreRetries; attempt++ {
+		err := s.client.Watch(ctx, txf, key)
+		if err == nil {
+			return bannedUntil, nil
+		}
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		return time.Time{}, fmt.Errorf("record ban failure: %w", err)
+	}
+
+	return time.Time{}, fmt.Errorf("record ban failure: too much contention on %s", addr)
+}
+
+func (s *redisBanStore) IsBanned(addr netip.Addr) (bool, error) {
+	state, err := s.get(context.Background(), addr)
+	if err != nil {
+		return false, err
+	}
+	return time.Now().Before(state.BannedUntil), nil
+}
+
+func (s *redisBanStore) Unban(addr netip.Addr) error {
+	if err := s.client.Del(context.Background(), s.key(addr)).Err(); err != nil {
+		return fmt.Errorf("delete ban entry: %w", err)
+	}
+	return nil
+}
+
+// List walks the ban keyspace with SCAN rather than KEYS: KEYS blocks the
+// whole Redis server until it has scanned every key, which is exactly what
+// you don't want on a keyspace meant to grow with a fleet of instances
+// recording failures.
+func (s *redisBanStore) List() ([]BanRecord, error) {
+	ctx := context.Background()
+
+	var records []BanRecord
+	iter := s.client.Scan(ctx, 0, banKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		addr, err := netip.ParseAddr(strings.TrimPrefix(key, banKeyPrefix))
+		if err != nil {
+			continue
+		}
+
+		state, err := s.get(ctx, addr)
+		if err != nil {
+			continue
+		}
+		records = append(records, BanRecord{Addr: addr, banState: state})
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("scan ban keys: %w", err)
+	}
+
+	return records, nil
+}
+
+func (s *redisBanStore) get(ctx context.Context, addr netip.Addr) (banState, error) {
+	return s.getCmdable(ctx, s.client, addr)
+}
+
+// getCmdable reads and decodes addr's ban state through cmd, which is either
+// s.client for a plain read or a *redis.Tx when called inside RecordFailure's
+// WATCH/MULTI transaction.
+func (s *redisBanStore) getCmdable(ctx context.Context, cmd redis.Cmdable, addr netip.Addr) (banState, error) {
+	var state banState
+
+	raw, err := cmd.Get(ctx, s.key(addr)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("get ban entry: %w", err)
+	}
+
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return state, fmt.Errorf("decode ban entry: %w", err)
+	}
+	return state, nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBanExpiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	base := time.Second
+	max := time.Hour
+
+	tests := []struct {
+		name string
+		over int
+		want time.Time
+	}{
+		{"no backoff yet", 0, now.Add(base)},
+		{"negative over treated as none", -1, now.Add(base)},
+		{"first backoff step doubles", 1, now.Add(2 * base)},
+		{"second backoff step quadruples", 2, now.Add(4 * base)},
+		{"capped at max before shift overflows", 20, now.Add(max)},
+		{"over guarded above 32", 33, now.Add(max)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := banExpiry(now, base, max, tt.over)
+			if !got.Equal(tt.want) {
+				t.Fatalf("banExpiry(over=%d) = %v, want %v", tt.over, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// isTrustedProxy reports whether addr is in the configured
+// --trusted-proxies list and is therefore allowed to supply client-IP
+// headers (X-Forwarded-For, X-Real-Ip, Forwarded).
+func (c *Controller) isTrustedProxy(addr netip.Addr) bool {
+	for _, cidr := range c.trustedProxies {
+		if cidr.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// pickClientIP walks hops right-to-left (closest proxy first) and returns
+// the first address that isn't itself a trusted proxy, i.e. the original
+// client IP.
+func pickClientIP(hops []netip.Addr, trusted []netip.Prefix) (netip.Addr, bool) {
+	for i := len(hops) - 1; i >= 0; i-- {
+		trustedHop := false
+		for _, cidr := range trusted {
+			if cidr.Contains(hops[i]) {
+				trustedHop = true
+				break
+			}
+		}
+		if trustedHop {
+			continue
+		}
+		return hops[i], true
+	}
+	return netip.Addr{}, false
+}
+
+// parseXFF splits a comma-separated X-Forwarded-For header into its hop
+// addresses, in header order (leftmost = original client).
+func parseXFF(header string) []netip.Addr {
+	var hops []netip.Addr
+	for _, raw := range strings.Split(header, ",") {
+		addr, err := netip.ParseAddr(strings.TrimSpace(raw))
+		if err != nil {
+			continue
+		}
+		hops = append(hops, addr)
+	}
+	return hops
+}
+
+// parseForwardedFor extracts the "for" node of each hop in an RFC 7239
+// Forwarded header, in header order. It understands quoted and bracketed
+// IPv6 forms such as `for="[::1]:1234"`.
+func parseForwardedFor(header string) []netip.Addr {
+	var hops []netip.Addr
+	for _, hop := range strings.Split(header, ",") {
+		for _, pair := range strings.Split(hop, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(key), "for") {
+				continue
+			}
+
+			addr, ok := parseForwardedNode(strings.Trim(strings.TrimSpace(value), `"`))
+			if ok {
+				hops = append(hops, addr)
+			}
+		}
+	}
+	return hops
+}
+
+// parseForwardedNode parses a single Forwarded "for" node, which may be a
+// bare IP, an "IP:port" pair, or a bracketed IPv6 address with an optional
+// port ("[::1]" or "[::1]:1234").
+func parseForwardedNode(node string) (netip.Addr, bool) {
+	if node == "" {
+		return netip.Addr{}, false
+	}
+
+	if addr, err := netip.ParseAddr(node); err == nil {
+		return addr, true
+	}
+
+	if strings.HasPrefix(node, "[") && !strings.Contains(node, "]:") {
+		addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(node, "["), "]"))
+		return addr, err == nil
+	}
+
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		if addr, err := netip.ParseAddr(host); err == nil {
+			return addr, true
+		}
+	}
+
+	return netip.Addr{}, false
+}
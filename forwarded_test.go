@@ -0,0 +1,160 @@
+package main
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestParseXFF(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []netip.Addr
+	}{
+		{
+			name:   "simple chain",
+			header: "203.0.113.1, 10.0.0.2, 10.0.0.1",
+			want: []netip.Addr{
+				netip.MustParseAddr("203.0.113.1"),
+				netip.MustParseAddr("10.0.0.2"),
+				netip.MustParseAddr("10.0.0.1"),
+			},
+		},
+		{
+			name:   "malformed entries are skipped",
+			header: "203.0.113.1, not-an-ip, , 10.0.0.1",
+			want: []netip.Addr{
+				netip.MustParseAddr("203.0.113.1"),
+				netip.MustParseAddr("10.0.0.1"),
+			},
+		},
+		{
+			name:   "entirely malformed yields nil",
+			header: "not-an-ip, also-bogus",
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseXFF(tt.header)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseXFF(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseForwardedNode(t *testing.T) {
+	tests := []struct {
+		name   string
+		node   string
+		want   netip.Addr
+		wantOK bool
+	}{
+		{"bare ipv4", "203.0.113.1", netip.MustParseAddr("203.0.113.1"), true},
+		{"ipv4 with port", "203.0.113.1:1234", netip.MustParseAddr("203.0.113.1"), true},
+		{"bracketed ipv6 no port", "[::1]", netip.MustParseAddr("::1"), true},
+		{"bracketed ipv6 with port", "[2001:db8::1]:8443", netip.MustParseAddr("2001:db8::1"), true},
+		{"empty node", "", netip.Addr{}, false},
+		{"garbage", "not-an-ip", netip.Addr{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseForwardedNode(tt.node)
+			if ok != tt.wantOK {
+				t.Fatalf("parseForwardedNode(%q) ok = %v, want %v", tt.node, ok, tt.wantOK)
+			}
+			if ok && got != tt.want {
+				t.Fatalf("parseForwardedNode(%q) = %v, want %v", tt.node, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseForwardedFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []netip.Addr
+	}{
+		{
+			name:   "quoted ipv4 and bracketed ipv6 with port",
+			header: `for="203.0.113.1";proto=https, for="[2001:db8::1]:8443"`,
+			want: []netip.Addr{
+				netip.MustParseAddr("203.0.113.1"),
+				netip.MustParseAddr("2001:db8::1"),
+			},
+		},
+		{
+			name:   "hop missing for= is skipped",
+			header: `proto=https;by=203.0.113.2, for=10.0.0.1`,
+			want: []netip.Addr{
+				netip.MustParseAddr("10.0.0.1"),
+			},
+		},
+		{
+			name:   "malformed for value yields nil",
+			header: `for=_obfuscated`,
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseForwardedFor(tt.header)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseForwardedFor(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPickClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	tests := []struct {
+		name   string
+		hops   []netip.Addr
+		want   netip.Addr
+		wantOK bool
+	}{
+		{
+			name: "skips trusted proxies to find client",
+			hops: []netip.Addr{
+				netip.MustParseAddr("203.0.113.1"),
+				netip.MustParseAddr("10.0.0.2"),
+				netip.MustParseAddr("10.0.0.1"),
+			},
+			want:   netip.MustParseAddr("203.0.113.1"),
+			wantOK: true,
+		},
+		{
+			name: "all hops trusted yields no client",
+			hops: []netip.Addr{
+				netip.MustParseAddr("10.1.2.3"),
+				netip.MustParseAddr("10.0.0.1"),
+			},
+			wantOK: false,
+		},
+		{
+			name:   "empty hop list",
+			hops:   nil,
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := pickClientIP(tt.hops, trusted)
+			if ok != tt.wantOK {
+				t.Fatalf("pickClientIP(%v) ok = %v, want %v", tt.hops, ok, tt.wantOK)
+			}
+			if ok && got != tt.want {
+				t.Fatalf("pickClientIP(%v) = %v, want %v", tt.hops, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+// newLogger builds the root logger for the given --log-format ("text",
+// "json", or "logfmt") and --verbosity level. slog's stdlib text handler
+// already emits key=value pairs, so "text" and "logfmt" share a handler
+// under two names operators commonly look for.
+func newLogger(format string, verbosity int) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: slog.Level(verbosity)}
+
+	var handler slog.Handler
+	switch format {
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	case "text", "logfmt", "":
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+
+	return slog.New(handler), nil
+}
+
+// requestID returns a short random hex identifier correlating a request's
+// access-log line with any error lines logged while handling it.
+func requestID() string {
+	var buf [8]byte
+	_, _ = rand.Read(buf[:])
+	return hex.EncodeToString(buf[:])
+}
+
+type requestStateKey struct{}
+
+// requestState accumulates the fields the access log needs, filled in as
+// HandleIP and BasicAuth reach their decision. logger is the per-request
+// child logger built by Controller.childLogger once remoteIP is known, so
+// every mid-request log line can carry the same request_id/remote_ip/
+// method/path/user_agent bundle as the final access-log line.
+type requestState struct {
+	reqID    string
+	remoteIP string
+	decision string
+	logger   *slog.Logger
+}
+
+// withRequestState attaches a fresh requestState to r's context, returning
+// both so the caller can read it back after the request has been handled.
+func withRequestState(r *http.Request) (*http.Request, *requestState) {
+	state := &requestState{reqID: requestID()}
+	return r.WithContext(context.WithValue(r.Context(), requestStateKey{}, state)), state
+}
+
+func requestStateFrom(ctx context.Context) *requestState {
+	if state, ok := ctx.Value(requestStateKey{}).(*requestState); ok {
+		return state
+	}
+	return &requestState{}
+}
+
+// statusWriter wraps http.ResponseWriter to capture the status code written
+// by HandleIP or the upstream proxy, for the access log.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// logAccess emits one structured access-log line per request.
+func (c *Controller) logAccess(r *http.Request, state *requestState, status int, latency time.Duration) {
+	c.logger.Info("request",
+		"request_id", state.reqID,
+		"remote_ip", state.remoteIP,
+		"method", r.Method,
+		"path", r.URL.Path,
+		"user_agent", r.UserAgent(),
+		"decision", state.decision,
+		"status", status,
+		"latency_ms", latency.Milliseconds(),
+	)
+}
+
+// childLogger builds the per-request logger, carrying request_id/method/
+// path/user_agent before the client IP is known. HandleIP widens this with
+// remote_ip as soon as ReadUserIP resolves it and caches the result on
+// state, so every mid-request log line is correlatable with the final
+// access-log line via request_id without downstream parsing heuristics.
+func (c *Controller) childLogger(r *http.Request, reqID string) *slog.Logger {
+	return c.logger.With(
+		"request_id", reqID,
+		"method", r.Method,
+		"path", r.URL.Path,
+		"user_agent", r.UserAgent(),
+	)
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
 	"log"
@@ -11,7 +13,6 @@ import (
 	"net/netip"
 	"net/url"
 	"os"
-	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -59,18 +60,15 @@ func lookupEnvDuration(key string, defaultVal time.Duration) time.Duration {
 }
 
 type Controller struct {
-	mutex           sync.Mutex // one to rule them all
-	allowedUsers    []User
-	bannedIPs       map[netip.Addr]uint
-	denyCIDR        []netip.Prefix
-	allowCIDRFix    []netip.Prefix
-	allowIPsDynamic []netip.Addr
-	denyPrivateIPs  bool
-	trustHeaders    bool
-	maxAttempts     int
-	mux             *http.ServeMux
-	tlsCert         string
-	tlsKey          string
+	auth           Auth
+	bans           BanStore
+	policy         *policyTrie
+	logger         *slog.Logger
+	clientCerts    *clientCertAuth
+	denyPrivateIPs bool
+	trustedProxies []netip.Prefix
+	mux            *http.ServeMux
+	tlsConfig      *tls.Config
 }
 
 func main() {
@@ -84,30 +82,124 @@ func main() {
 		listenAddrTLSAny = flag.String("tls-listen", lookupEnvString("TLS_LISTEN", ":8180"), "listen for IPv4/IPv6 TLS connections")
 		listenAddrTLS4   = flag.String("tls-listen4", lookupEnvString("TLS_LISTEN4", ":8184"), "listen for IPv4 TLS connections")
 		listenAddrTLS6   = flag.String("tls-listen6", lookupEnvString("TLS_LISTEN6", ":8186"), "listen for IPv6 TLS connections")
+		tlsMinVersion    = flag.String("tls-min-version", lookupEnvString("TLS_MIN_VERSION", "1.2"), "minimum TLS version: 1.2 or 1.3")
+		tlsCipherSuites  = flag.String("tls-cipher-suites", lookupEnvString("TLS_CIPHER_SUITES", ""), "comma-separated cipher suite names to restrict to (see --list-ciphers); empty keeps Go's defaults")
+		listCiphers      = flag.Bool("list-ciphers", false, "print supported TLS cipher suite names and exit")
+		tlsClientCA      = flag.String("tls-client-ca", lookupEnvString("TLS_CLIENT_CA", ""), "CA bundle enabling optional mTLS client-certificate auth")
+		tlsClientNames   = flag.String("tls-client-allowed-names", lookupEnvString("TLS_CLIENT_ALLOWED_NAMES", ""), "client cert CN/SAN values allowed to bypass basic auth (requires --tls-client-ca)")
+		tlsACME          = flag.Bool("tls-acme", lookupEnvBool("TLS_ACME", false), "obtain certificates automatically via ACME instead of --tls-cert/--tls-key")
+		tlsACMEHosts     = flag.String("tls-acme-hosts", lookupEnvString("TLS_ACME_HOSTS", ""), "hostnames to request ACME certificates for, used when --tls-acme")
+		tlsACMECacheDir  = flag.String("tls-acme-cache-dir", lookupEnvString("TLS_ACME_CACHE_DIR", "acme-cache"), "directory caching ACME account/certificate state")
 		target           = flag.String("target", lookupEnvString("TARGET", ""), "proxy to the given target")
 		verbosity        = flag.Int("verbosity", lookupEnvInt("VERBOSITY", 0), "-4 Debug, 0 Info, 4 Warn, 8 Error")
+		logFormat        = flag.String("log-format", lookupEnvString("LOG_FORMAT", "text"), "log output format: text, json, or logfmt")
 		maxAttempts      = flag.Int("max-attempts", lookupEnvInt("MAX_ATTEMPTS", 10), "ban IP after max failed auth attempts")
+		banStoreFlag     = flag.String("ban-store", lookupEnvString("BAN_STORE", "memory"), "ban store backend: memory, bolt, or redis")
+		banBaseDelay     = flag.Duration("ban-base-delay", lookupEnvDuration("BAN_BASE_DELAY", time.Minute), "ban duration once --max-attempts is reached, doubled per additional failed attempt")
+		banMaxDuration   = flag.Duration("ban-max-duration", lookupEnvDuration("BAN_MAX_DURATION", 24*time.Hour), "upper bound for the exponential ban backoff")
+		banBoltPath      = flag.String("ban-bolt-path", lookupEnvString("BAN_BOLT_PATH", "basic-ip-auth-bans.db"), "BoltDB file path, used when --ban-store=bolt")
+		banRedisAddr     = flag.String("ban-redis-addr", lookupEnvString("BAN_REDIS_ADDR", "localhost:6379"), "Redis address, used when --ban-store=redis")
 		usersFlag        = flag.String("users", lookupEnvString("USERS", ""), "allow the given basic auth credentals (e.g. user1:pass1,user2:pass2)")
+		usersFileFlag    = flag.String("users-file", lookupEnvString("USERS_FILE", ""), "path to an htpasswd-style file of allowed users (bcrypt, SHA, MD5)")
+		usersFileReload  = flag.Duration("users-file-reload-interval", lookupEnvDuration("USERS_FILE_RELOAD_INTERVAL", time.Minute), "how often to re-read --users-file")
 		allowHostsFlag   = flag.String("allow-hosts", lookupEnvString("ALLOW_HOSTS", ""), "allow the given host IPs (e.g. example.com)")
 		allowCIDRFlag    = flag.String("allow-cidr", lookupEnvString("ALLOW_CIDR", ""), "allow the given CIDR (e.g. 10.0.0.0/8,192.168.0.0/16)")
 		denyCIDRFlag     = flag.String("deny-cidr", lookupEnvString("DENY_CIDR", ""), "block the given CIDR (e.g. 10.0.0.0/8,192.168.0.0/16)")
 		denyPrivateIPs   = flag.Bool("deny-private", lookupEnvBool("DENY_PRIVATE", false), "deny IPs from the private network space")
-		trustHeaders     = flag.Bool("trust-headers", lookupEnvBool("TRUST_HEADERS", false), "trust X-Real-Ip and X-Forwarded-For headers")
+		trustedProxies   = flag.String("trusted-proxies", lookupEnvString("TRUSTED_PROXIES", ""), "CIDRs allowed to set X-Real-Ip, X-Forwarded-For or Forwarded (e.g. 10.0.0.0/8,192.168.0.0/16)")
 		resetInterval    = flag.Duration("reset-interval", lookupEnvDuration("RESET_INTERVAL", 7*24*time.Hour), "Cleanup dynamic IPs and renew host IPs")
 	)
 	flag.Parse()
 
-	slog.SetLogLoggerLevel(slog.Level(*verbosity))
+	if *listCiphers {
+		ListCiphers()
+		return
+	}
+
+	logger, err := newLogger(*logFormat, *verbosity)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	slog.SetDefault(logger)
 
 	c := Controller{
-		maxAttempts:    *maxAttempts,
-		bannedIPs:      make(map[netip.Addr]uint),
+		logger:         logger,
 		denyPrivateIPs: *denyPrivateIPs,
-		trustHeaders:   *trustHeaders,
-		tlsCert:        *tlsCert,
-		tlsKey:         *tlsKey,
 	}
 
+	var tlsConfig *tls.Config
+	switch {
+	case *tlsACME:
+		tlsConfig = newACMETLSConfig(strings.Split(*tlsACMEHosts, ","), *tlsACMECacheDir)
+	case *tlsCert != "" && *tlsKey != "":
+		reloader, err := newCertReloader(*tlsCert, *tlsKey)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		tlsConfig = &tls.Config{GetCertificate: reloader.GetCertificate}
+	}
+
+	if tlsConfig != nil {
+		minVersion, err := parseTLSVersion(*tlsMinVersion)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		tlsConfig.MinVersion = minVersion
+
+		cipherSuites, err := parseCipherSuites(*tlsCipherSuites)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		tlsConfig.CipherSuites = cipherSuites
+
+		if *tlsClientCA != "" {
+			caCert, err := os.ReadFile(*tlsClientCA)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(caCert) {
+				log.Fatalln("no certificates found in --tls-client-ca")
+			}
+			tlsConfig.ClientCAs = pool
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+
+			var allowedNames []string
+			for _, name := range strings.Split(*tlsClientNames, ",") {
+				if name != "" {
+					allowedNames = append(allowedNames, name)
+				}
+			}
+			c.clientCerts = newClientCertAuth(allowedNames)
+		}
+	}
+	c.tlsConfig = tlsConfig
+
+	switch *banStoreFlag {
+	case "memory", "":
+		c.bans = newMemoryBanStore(*maxAttempts, *banBaseDelay, *banMaxDuration)
+	case "bolt":
+		bans, err := newBoltBanStore(*banBoltPath, *maxAttempts, *banBaseDelay, *banMaxDuration)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		c.bans = bans
+	case "redis":
+		c.bans = newRedisBanStore(*banRedisAddr, *maxAttempts, *banBaseDelay, *banMaxDuration)
+	default:
+		log.Fatalf("unknown --ban-store %q", *banStoreFlag)
+	}
+
+	trustedProxyCIDRs := strings.Split(*trustedProxies, ",")
+	if len(trustedProxyCIDRs) > 0 && trustedProxyCIDRs[0] != "" {
+		for _, cidr := range trustedProxyCIDRs {
+			c.trustedProxies = append(c.trustedProxies, netip.MustParsePrefix(cidr))
+		}
+	}
+
+	var authProviders multiAuth
+
+	var staticUsers []User
 	allowedUsers := strings.Split(*usersFlag, ",")
 	for _, user := range allowedUsers {
 		if user == "" {
@@ -118,23 +210,42 @@ func main() {
 			slog.Error("malformed user", "user", namePass)
 			continue
 		}
-		c.allowedUsers = append(c.allowedUsers, User{Name: namePass[0], Password: namePass[1]})
+		staticUsers = append(staticUsers, User{Name: namePass[0], Password: namePass[1]})
+	}
+	if len(staticUsers) > 0 {
+		authProviders = append(authProviders, newStaticAuth(staticUsers))
 	}
 
+	if *usersFileFlag != "" {
+		fa, err := newFileAuth(*usersFileFlag, *usersFileReload, c.logger)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		authProviders = append(authProviders, fa)
+	}
+
+	if len(authProviders) > 0 {
+		c.auth = authProviders
+	}
+
+	var allowCIDRFix []netip.Prefix
 	allowedIPs := strings.Split(*allowCIDRFlag, ",")
 	if len(allowedIPs) > 0 && allowedIPs[0] != "" {
 		for _, ip := range allowedIPs {
-			c.allowCIDRFix = append(c.allowCIDRFix, netip.MustParsePrefix(ip))
+			allowCIDRFix = append(allowCIDRFix, netip.MustParsePrefix(ip))
 		}
 	}
 
+	var denyCIDR []netip.Prefix
 	deniedIPs := strings.Split(*denyCIDRFlag, ",")
 	if len(deniedIPs) > 0 && deniedIPs[0] != "" {
 		for _, ip := range deniedIPs {
-			c.denyCIDR = append(c.denyCIDR, netip.MustParsePrefix(ip))
+			denyCIDR = append(denyCIDR, netip.MustParsePrefix(ip))
 		}
 	}
 
+	c.policy = newPolicyTrie(denyCIDR, allowCIDRFix)
+
 	// Add dynamic IPs and renew frequently
 	allowedHosts := strings.Split(*allowHostsFlag, ",")
 	if len(allowedHosts) > 0 && allowedHosts[0] != "" {
@@ -149,6 +260,7 @@ func main() {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", c.ProxyRequestHandler(proxy))
 	mux.HandleFunc(*statusPath, c.Status)
+	mux.HandleFunc(*statusPath+"/admin/bans", c.AdminBansHandler)
 
 	c.mux = mux
 
@@ -164,7 +276,7 @@ func main() {
 		if addr == "" {
 			continue
 		}
-		if c.tlsCert == "" || c.tlsKey == "" {
+		if c.tlsConfig == nil {
 			continue
 		}
 
@@ -176,10 +288,11 @@ func main() {
 	wg.Wait()
 }
 
-func (c *Controller) listen(tls bool, addr, network string) {
+func (c *Controller) listen(useTLS bool, addr, network string) {
 	srv := &http.Server{
-		Addr:    addr,
-		Handler: c.mux,
+		Addr:      addr,
+		Handler:   c.mux,
+		TLSConfig: c.tlsConfig,
 	}
 
 	listen, err := net.Listen(network, addr)
@@ -188,10 +301,10 @@ func (c *Controller) listen(tls bool, addr, network string) {
 	}
 
 	go func() {
-		slog.Info("listening", "addr", addr, "network", network, "tls", tls)
+		slog.Info("listening", "addr", addr, "network", network, "tls", useTLS)
 
-		if tls {
-			if err := srv.ServeTLS(listen, c.tlsCert, c.tlsKey); err != nil {
+		if useTLS {
+			if err := srv.ServeTLS(listen, "", ""); err != nil {
 				log.Fatalln(err)
 			}
 		} else {
@@ -211,9 +324,7 @@ func (c *Controller) generateDynamicIPs(resetInterval time.Duration, allowedHost
 
 		time.Sleep(resetInterval)
 		slog.Info("renewing dynamic IPs")
-		c.mutex.Lock()
-		c.allowIPsDynamic = []netip.Addr{}
-		c.mutex.Unlock()
+		c.policy.reset()
 	}
 }
 
@@ -229,9 +340,7 @@ func (c *Controller) allowHost(host string) {
 		if !ok {
 			continue
 		}
-		c.mutex.Lock()
-		c.allowIPsDynamic = append(c.allowIPsDynamic, nip)
-		c.mutex.Unlock()
+		c.policy.allowDynamic(nip)
 		slog.Info("added ip from host", "host", host, "ip", nip.String())
 	}
 }
@@ -257,132 +366,168 @@ type User struct {
 	Password string
 }
 
-func (c *Controller) BasicAuth(requestIP netip.Addr, w http.ResponseWriter, r *http.Request) error {
-	c.mutex.Lock()
-	attempts := c.bannedIPs[requestIP]
-	c.mutex.Unlock()
+func (c *Controller) BasicAuth(requestIP netip.Addr, w http.ResponseWriter, r *http.Request, logger *slog.Logger) error {
+	state := requestStateFrom(r.Context())
 
-	if attempts >= uint(c.maxAttempts) {
+	banned, err := c.bans.IsBanned(requestIP)
+	if err != nil {
+		return fmt.Errorf("check ban store: %w", err)
+	}
+	if banned {
+		state.decision = "banned"
 		return fmt.Errorf("IP is banned (addr=%s)", requestIP.String())
 	}
 
 	givenUser, givenPass, _ := r.BasicAuth()
 
-	if len(c.allowedUsers) == 0 {
+	if c.auth == nil {
 		return fmt.Errorf("basic auth disabled (no users specified)")
 	}
 
-	for _, user := range c.allowedUsers {
-		if givenUser == user.Name && givenPass == user.Password {
-			slog.Info("success basic auth (address dynamically added)", "addr", requestIP.String(), "user", user.Name)
-			return nil
-		}
+	if c.auth.Authenticate(givenUser, givenPass) {
+		logger.Info("success basic auth (address dynamically added)", "user", givenUser)
+		return nil
+	}
+
+	bannedUntil, banErr := c.bans.RecordFailure(requestIP)
+	if banErr != nil {
+		logger.Error("record ban failure", "error", banErr)
 	}
 
-	c.mutex.Lock()
-	attempts = c.bannedIPs[requestIP]
-	c.bannedIPs[requestIP] = attempts + 1
-	c.mutex.Unlock()
+	state.decision = "basic_auth_failed"
 
-	return fmt.Errorf("failed basic auth (user=%s addr=%s attempts=%d)", givenUser, requestIP, attempts)
+	return fmt.Errorf("failed basic auth (user=%s addr=%s banned_until=%s)", givenUser, requestIP, bannedUntil.Format(time.RFC3339))
 }
 
-func (c *Controller) HandleIPWrapper(w http.ResponseWriter, r *http.Request) {
-	err := c.HandleIP(w, r)
+// ReadUserIP returns the client's IP address. X-Real-Ip, X-Forwarded-For and
+// RFC 7239 Forwarded headers are only honored when r.RemoteAddr is in the
+// --trusted-proxies allowlist; otherwise a spoofed header from an untrusted
+// upstream would let any client claim an arbitrary source IP.
+func (c *Controller) ReadUserIP(r *http.Request, logger *slog.Logger) (netip.Addr, error) {
+	remoteAddr, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
-		slog.Error("failed handling ip", "error", err)
+		return netip.Addr{}, fmt.Errorf("split host port: %w", err)
+	}
+
+	remoteIP, err := netip.ParseAddr(remoteAddr)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("parse remote addr: %w", err)
 	}
-}
 
-func (c *Controller) ReadUserIP(r *http.Request) (netip.Addr, error) {
-	if c.trustHeaders {
-		if ip := r.Header.Get("X-Real-Ip"); ip != "" {
-			slog.Debug("IP from X-Real-Ip", "addr", ip)
-			return netip.ParseAddr(ip)
+	if !c.isTrustedProxy(remoteIP) {
+		logger.Debug("IP from request", "addr", remoteIP)
+		return remoteIP, nil
+	}
+
+	if fwd := r.Header.Get("Forwarded"); fwd != "" {
+		if ip, ok := pickClientIP(parseForwardedFor(fwd), c.trustedProxies); ok {
+			logger.Debug("IP from Forwarded", "addr", ip)
+			return ip, nil
 		}
-		if ip := r.Header.Get("X-Forwarded-For"); ip != "" {
-			slog.Debug("IP from X-Forwarded-For", "addr", ip)
-			return netip.ParseAddr(ip)
+	}
+
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		if ip, ok := pickClientIP(parseXFF(xff), c.trustedProxies); ok {
+			logger.Debug("IP from X-Forwarded-For", "addr", ip)
+			return ip, nil
 		}
 	}
 
-	addr, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return netip.Addr{}, fmt.Errorf("split host port: %w", err)
+	if realIP := r.Header.Get("X-Real-Ip"); realIP != "" {
+		if ip, err := netip.ParseAddr(realIP); err == nil {
+			logger.Debug("IP from X-Real-Ip", "addr", ip)
+			return ip, nil
+		}
 	}
 
-	slog.Debug("IP from request", "addr", addr)
+	logger.Debug("IP from request (no usable proxy header)", "addr", remoteIP)
 
-	return netip.ParseAddr(addr)
+	return remoteIP, nil
 }
 
 func (c *Controller) HandleIP(w http.ResponseWriter, r *http.Request) (err error) {
+	state := requestStateFrom(r.Context())
+	logger := c.childLogger(r, state.reqID)
+
 	defer func() {
 		if err == nil {
 			return
 		}
-		slog.Error("failed allow IP", "error", err)
+		logger.Error("failed allow IP", "decision", state.decision, "error", err)
 		w.Header().Set("WWW-Authenticate", `Basic realm=""`)
 		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 	}()
 
-	requestIP, err := c.ReadUserIP(r)
+	requestIP, err := c.ReadUserIP(r, logger)
 	if err != nil {
 		return err
 	}
+	state.remoteIP = requestIP.String()
+	logger = logger.With("remote_ip", state.remoteIP)
+	state.logger = logger
 
 	if c.denyPrivateIPs {
 		if requestIP.IsPrivate() || requestIP.IsLoopback() || requestIP.IsLinkLocalUnicast() || requestIP.IsLinkLocalMulticast() {
+			state.decision = "denied_private"
 			return fmt.Errorf("private IPs are blocked (addr=%s)", requestIP.String())
 		}
 	}
 
-	for _, cidr := range c.denyCIDR {
-		if cidr.Contains(requestIP) {
+	if decision, ok := c.policy.lookup(requestIP); ok {
+		switch decision {
+		case DecisionDeny:
+			state.decision = "denied_cidr"
 			return fmt.Errorf("in deny list (addr=%s)", requestIP.String())
+		case DecisionAllowFixed:
+			state.decision = "allowed_cidr_fix"
+			logger.Debug("in allow list (fixed ip)", "decision", state.decision)
+			return nil
+		case DecisionAllowDynamic:
+			state.decision = "allowed_dynamic"
+			logger.Debug("in allow list (dynamic ip)", "decision", state.decision)
+			return nil
 		}
 	}
 
-	for _, cidr := range c.allowCIDRFix {
-		if cidr.Contains(requestIP) {
-			slog.Debug("in allow list (fixed ip)", "addr", requestIP.String())
+	logger.Debug("not in allow list")
+
+	if c.clientCerts != nil && r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		cert := r.TLS.PeerCertificates[0]
+		if c.clientCerts.matches(cert) {
+			state.decision = "client_cert"
+			logger.Debug("allowed via client certificate", "decision", state.decision, "cn", cert.Subject.CommonName)
 			return nil
 		}
 	}
 
-	if slices.Contains(c.allowIPsDynamic, requestIP) {
-		slog.Debug("in allow list (dynamic ip)", "addr", requestIP.String())
-		return nil
-	}
-
-	slog.Debug("not in allow list", "addr", requestIP)
-
-	err = c.BasicAuth(requestIP, w, r)
+	err = c.BasicAuth(requestIP, w, r, logger)
 	if err != nil {
 		return err
 	}
 
-	slog.Debug("allowed", "addr", requestIP)
-	c.mutex.Lock()
-	c.allowIPsDynamic = append(c.allowIPsDynamic, requestIP)
-	c.mutex.Unlock()
+	state.decision = "basic_auth_success"
+	logger.Debug("allowed", "decision", state.decision)
+	c.policy.allowDynamic(requestIP)
 	return nil
 }
 
 func (c *Controller) ProxyRequestHandler(proxy *httputil.ReverseProxy) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
 
-		err := c.HandleIP(w, r)
-		if err != nil {
-			return
+		r, state := withRequestState(r)
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+		if err := c.HandleIP(sw, r); err == nil {
+			proxy.ServeHTTP(sw, r)
 		}
 
-		proxy.ServeHTTP(w, r)
+		c.logAccess(r, state, sw.status, time.Since(start))
 	}
 }
 
 func (c *Controller) Status(w http.ResponseWriter, r *http.Request) {
-	requestIP, err := c.ReadUserIP(r)
+	requestIP, err := c.ReadUserIP(r, c.logger)
 	if err != nil {
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
@@ -394,28 +539,20 @@ func (c *Controller) Status(w http.ResponseWriter, r *http.Request) {
 		status = fmt.Sprintf("denied (private IP)")
 	}
 
-	for ip, attempts := range c.bannedIPs {
-		if ip == requestIP && attempts >= uint(c.maxAttempts) {
-			status = fmt.Sprintf("banned")
-			break
-		}
+	if banned, err := c.bans.IsBanned(requestIP); err == nil && banned {
+		status = "banned"
 	}
 
-	for _, cidr := range c.denyCIDR {
-		if cidr.Contains(requestIP) {
-			status = fmt.Sprintf("denied CIDR (%s)", cidr.String())
-			break
-		}
-	}
-	for _, cidr := range c.allowCIDRFix {
-		if cidr.Contains(requestIP) {
-			status = fmt.Sprintf("allowed CIDR (%s)", cidr.String())
-			break
+	if decision, ok := c.policy.lookup(requestIP); ok {
+		switch decision {
+		case DecisionDeny:
+			status = "denied CIDR"
+		case DecisionAllowFixed:
+			status = "allowed CIDR"
+		case DecisionAllowDynamic:
+			status = "allowed dynamic IP"
 		}
 	}
-	if slices.Contains(c.allowIPsDynamic, requestIP) {
-		status = fmt.Sprintf("allowed dynamic IP")
-	}
 
 	w.Write([]byte(fmt.Sprintf("ip: %s\n", requestIP)))
 	w.Write([]byte(fmt.Sprintf("status: %s\n", status)))
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net/netip"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gaissmai/bart"
+)
+
+// Decision is the verdict attached to a prefix in the policy trie.
+type Decision int
+
+const (
+	// DecisionDeny marks a prefix as blocked outright.
+	DecisionDeny Decision = iota
+	// DecisionAllowFixed marks a prefix allowed via --allow-cidr or --allow-hosts.
+	DecisionAllowFixed
+	// DecisionAllowDynamic marks a single address allowed after a successful basic auth.
+	DecisionAllowDynamic
+)
+
+// policyTrie answers longest-prefix-match allow/deny lookups for both IPv4
+// and IPv6 in a single structure, replacing the old per-list linear scans.
+// Unlike a plain LPM trie, deny always takes precedence over allow: a
+// --deny-cidr blocks everything it covers even if a more specific
+// --allow-cidr (or a dynamically-allowed address from basic auth) would
+// otherwise match. See deniedBySupernet and allowDynamic's deny check.
+//
+// staticTable holds the CIDRs configured at startup (--allow-cidr,
+// --deny-cidr, --allow-hosts) and is never mutated afterwards. table is the
+// live lookup table readers hit on the hot path; it starts as a clone of
+// staticTable and gains /32 or /128 entries as clients pass basic auth.
+// Writers (the dynamic-IP updater and the periodic reset) clone-mutate-swap
+// under mutex so reads via table.Load() stay lock-free.
+type policyTrie struct {
+	writeMu     sync.Mutex // serializes clone-mutate-swap writers; readers never block on this
+	staticTable *bart.Table[Decision]
+	table       atomic.Pointer[bart.Table[Decision]]
+}
+
+func newPolicyTrie(denyCIDR, allowCIDRFix []netip.Prefix) *policyTrie {
+	static := new(bart.Table[Decision])
+	for _, pfx := range denyCIDR {
+		static.Insert(pfx, DecisionDeny)
+	}
+	for _, pfx := range allowCIDRFix {
+		// A plain LPM trie would let an --allow-cidr silently override a
+		// broader (or identical) --deny-cidr just because it's more
+		// specific. Deny must always win, so skip the allow entry if it or
+		// any of its supernets is already denied.
+		if deniedBySupernet(static, pfx) {
+			continue
+		}
+		static.Insert(pfx, DecisionAllowFixed)
+	}
+
+	p := &policyTrie{staticTable: static}
+	p.table.Store(static.Clone())
+	return p
+}
+
+// deniedBySupernet reports whether pfx, or any less-specific prefix covering
+// it, already carries a deny decision in table.
+func deniedBySupernet(table *bart.Table[Decision], pfx netip.Prefix) bool {
+	for _, decision := range table.Supernets(pfx) {
+		if decision == DecisionDeny {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the longest-prefix-match decision for addr, if any.
+func (p *policyTrie) lookup(addr netip.Addr) (Decision, bool) {
+	return p.table.Load().Lookup(addr)
+}
+
+// allowDynamic inserts addr as a /32 (or /128 for IPv6) allow entry into the
+// live table, unless addr is already covered by a deny entry: a successful
+// basic auth must not let a client bypass an explicit --deny-cidr.
+func (p *policyTrie) allowDynamic(addr netip.Addr) {
+	pfx := netip.PrefixFrom(addr, addr.BitLen())
+
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	current := p.table.Load()
+	if decision, ok := current.Lookup(addr); ok && decision == DecisionDeny {
+		return
+	}
+	next := current.Clone()
+	next.Insert(pfx, DecisionAllowDynamic)
+	p.table.Store(next)
+}
+
+// reset drops all dynamically allowed addresses, restoring the live table to
+// the static allow/deny CIDRs configured at startup.
+func (p *policyTrie) reset() {
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	p.table.Store(p.staticTable.Clone())
+}
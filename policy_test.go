@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestNewPolicyTrieDenyWinsOverAllow(t *testing.T) {
+	tests := []struct {
+		name   string
+		deny   []string
+		allow  []string
+		lookup string
+		want   Decision
+		wantOK bool
+	}{
+		{
+			name:   "more specific allow nested in broader deny",
+			deny:   []string{"10.0.0.0/8"},
+			allow:  []string{"10.0.0.0/16"},
+			lookup: "10.0.5.5",
+			want:   DecisionDeny,
+			wantOK: true,
+		},
+		{
+			name:   "identical deny and allow prefix",
+			deny:   []string{"192.168.1.0/24"},
+			allow:  []string{"192.168.1.0/24"},
+			lookup: "192.168.1.42",
+			want:   DecisionDeny,
+			wantOK: true,
+		},
+		{
+			name:   "allow outside any deny still wins",
+			deny:   []string{"10.0.0.0/8"},
+			allow:  []string{"172.16.0.0/12"},
+			lookup: "172.16.5.5",
+			want:   DecisionAllowFixed,
+			wantOK: true,
+		},
+		{
+			name:   "no matching prefix",
+			deny:   []string{"10.0.0.0/8"},
+			lookup: "8.8.8.8",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trie := newPolicyTrie(parsePrefixes(t, tt.deny), parsePrefixes(t, tt.allow))
+
+			got, ok := trie.lookup(netip.MustParseAddr(tt.lookup))
+			if ok != tt.wantOK {
+				t.Fatalf("lookup(%s) ok = %v, want %v", tt.lookup, ok, tt.wantOK)
+			}
+			if ok && got != tt.want {
+				t.Fatalf("lookup(%s) = %v, want %v", tt.lookup, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyTrieAllowDynamicRespectsDeny(t *testing.T) {
+	trie := newPolicyTrie(parsePrefixes(t, []string{"10.0.0.0/8"}), nil)
+
+	deniedAddr := netip.MustParseAddr("10.1.2.3")
+	trie.allowDynamic(deniedAddr)
+
+	got, ok := trie.lookup(deniedAddr)
+	if !ok || got != DecisionDeny {
+		t.Fatalf("lookup(%s) = (%v, %v), want (DecisionDeny, true)", deniedAddr, got, ok)
+	}
+
+	allowedAddr := netip.MustParseAddr("203.0.113.1")
+	trie.allowDynamic(allowedAddr)
+
+	got, ok = trie.lookup(allowedAddr)
+	if !ok || got != DecisionAllowDynamic {
+		t.Fatalf("lookup(%s) = (%v, %v), want (DecisionAllowDynamic, true)", allowedAddr, got, ok)
+	}
+}
+
+func TestPolicyTrieReset(t *testing.T) {
+	trie := newPolicyTrie(parsePrefixes(t, []string{"10.0.0.0/8"}), nil)
+
+	addr := netip.MustParseAddr("203.0.113.1")
+	trie.allowDynamic(addr)
+	if _, ok := trie.lookup(addr); !ok {
+		t.Fatalf("lookup(%s) ok = false after allowDynamic, want true", addr)
+	}
+
+	trie.reset()
+	if _, ok := trie.lookup(addr); ok {
+		t.Fatalf("lookup(%s) ok = true after reset, want false", addr)
+	}
+}
+
+func parsePrefixes(t *testing.T, cidrs []string) []netip.Prefix {
+	t.Helper()
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefixes = append(prefixes, netip.MustParsePrefix(cidr))
+	}
+	return prefixes
+}
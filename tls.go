@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// newACMETLSConfig builds a tls.Config that obtains certificates
+// automatically via ACME (e.g. Let's Encrypt) for the given hostnames,
+// caching account and certificate state under cacheDir. Used when
+// --tls-acme is set, in place of --tls-cert/--tls-key.
+func newACMETLSConfig(hosts []string, cacheDir string) *tls.Config {
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(hosts...),
+		Cache:      autocert.DirCache(cacheDir),
+	}
+	return manager.TLSConfig()
+}
+
+// cipherSuitesByName indexes every cipher suite the running Go TLS stack
+// supports (secure and insecure) by its canonical name, for --tls-cipher-suites.
+var cipherSuitesByName = func() map[string]uint16 {
+	m := make(map[string]uint16, 32)
+	for _, suite := range tls.CipherSuites() {
+		m[suite.Name] = suite.ID
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		m[suite.Name] = suite.ID
+	}
+	return m
+}()
+
+// ListCiphers prints the name of every supported cipher suite, one per
+// line, for the --list-ciphers helper command.
+func ListCiphers() {
+	for _, suite := range tls.CipherSuites() {
+		fmt.Println(suite.Name)
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		fmt.Println(suite.Name, "(insecure)")
+	}
+}
+
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported --tls-min-version %q (want 1.2 or 1.3)", version)
+	}
+}
+
+func parseCipherSuites(names string) ([]uint16, error) {
+	if names == "" {
+		return nil, nil
+	}
+
+	var ids []uint16
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		id, ok := cipherSuitesByName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite %q (see --list-ciphers)", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// certReloader serves a cert/key pair loaded from disk via
+// tls.Config.GetCertificate, re-reading the files whenever their mtime
+// changes so a renewed certificate is picked up without a restart.
+type certReloader struct {
+	mutex    sync.RWMutex
+	cert     *tls.Certificate
+	certPath string
+	keyPath  string
+	modTime  time.Time
+}
+
+func newCertReloader(certPath, keyPath string) (*certReloader, error) {
+	r := &certReloader{certPath: certPath, keyPath: keyPath}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) reload() error {
+	info, err := os.Stat(r.certPath)
+	if err != nil {
+		return fmt.Errorf("stat tls cert: %w", err)
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return fmt.Errorf("load tls cert/key pair: %w", err)
+	}
+
+	r.mutex.Lock()
+	r.cert = &cert
+	r.modTime = info.ModTime()
+	r.mutex.Unlock()
+
+	return nil
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if info, err := os.Stat(r.certPath); err == nil {
+		r.mutex.RLock()
+		stale := info.ModTime().After(r.modTime)
+		r.mutex.RUnlock()
+
+		if stale {
+			if err := r.reload(); err != nil {
+				slog.Error("reload tls cert", "path", r.certPath, "error", err)
+			}
+		}
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.cert, nil
+}
+
+// clientCertAuth allows a client to skip basic auth entirely by presenting
+// a TLS client certificate whose CN or SAN matches --tls-client-allowed-names.
+type clientCertAuth struct {
+	allowed map[string]struct{}
+}
+
+func newClientCertAuth(names []string) *clientCertAuth {
+	allowed := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		allowed[name] = struct{}{}
+	}
+	return &clientCertAuth{allowed: allowed}
+}
+
+func (a *clientCertAuth) matches(cert *x509.Certificate) bool {
+	if _, ok := a.allowed[cert.Subject.CommonName]; ok {
+		return true
+	}
+	for _, name := range cert.DNSNames {
+		if _, ok := a.allowed[name]; ok {
+			return true
+		}
+	}
+	for _, ip := range cert.IPAddresses {
+		if _, ok := a.allowed[ip.String()]; ok {
+			return true
+		}
+	}
+	return false
+}